@@ -0,0 +1,68 @@
+// command mdns queries for mdns services.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/saljam/mdns"
+)
+
+func main() {
+	timeout := flag.Duration("timeout", 2*time.Second, "how long to wait for answers, 0 means indefinitely")
+	txtKeys := flag.String("txt", "path,model", "comma-separated TXT keys to print, if present")
+	log.SetFlags(0)
+	flag.Parse()
+
+	ctx := context.Background()
+	if *timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	keys := strings.Split(*txtKeys, ",")
+
+	r, err := mdns.NewResolver()
+	if err != nil {
+		log.Fatalf("could not create resolver: %v", err)
+	}
+	entries, err := r.Scan(ctx, *timeout)
+	if err != nil {
+		log.Fatalf("could not query: %v", err)
+	}
+	for e := range entries {
+		name, service, proto := splitInstance(e.Name)
+		fmt.Printf("%s\t%s\t%s:%d\t%s\t%s\n", proto, service, e.Host, e.Port, name, selectTXT(e.TXT, keys))
+	}
+}
+
+// selectTXT renders the requested TXT keys present in txt as "k=v k=v ...".
+func selectTXT(txt map[string]string, keys []string) string {
+	var parts []string
+	for _, k := range keys {
+		if v, ok := txt[k]; ok {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// splitInstance pulls the instance name and service/proto labels out of
+// a PTR instance name like "My Printer._ipp._tcp.local.".
+func splitInstance(instance string) (name, service, proto string) {
+	parts := strings.SplitN(strings.TrimSuffix(instance, "."), ".", 2)
+	name = parts[0]
+	if len(parts) != 2 {
+		return name, "", ""
+	}
+	svcParts := strings.Split(parts[1], ".")
+	if len(svcParts) == 3 && svcParts[2] == "local" {
+		service = strings.Trim(svcParts[0], "_")
+		proto = strings.Trim(svcParts[1], "_")
+	}
+	return name, service, proto
+}
@@ -0,0 +1,95 @@
+package mdns
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// ServiceEntry describes a service discovered (or being assembled) from
+// mDNS records. A consumer should treat an entry as provisional until
+// complete reports true: the PTR answer alone only gives a name, the
+// SRV/A/AAAA/TXT records that describe it can arrive in any order or in
+// a later packet.
+type ServiceEntry struct {
+	Name string // PTR instance name, e.g. "My Printer._ipp._tcp.local."
+	Host string // SRV target without its trailing dot, e.g. "printer.local"
+	Port int
+
+	AddrV4 net.IP
+	AddrV6 net.IP
+
+	TTL uint32 // TTL of the SRV record this entry was built from, in seconds
+
+	TXTRaw []string          // TXT record segments, unparsed
+	TXT    map[string]string // TXTRaw parsed per RFC 6763 Section 6
+
+	hasSRV bool
+	hasTXT bool
+}
+
+// parseTXT splits TXT record segments into key/value pairs per RFC 6763
+// Section 6: each segment is "key=value", split on the first '='; a
+// segment with no '=' is a boolean key present with an empty value; keys
+// are lowercased, and the first occurrence of a duplicate key wins. A
+// zero-length segment isn't a valid key=value pair and is skipped.
+func parseTXT(raw []string) map[string]string {
+	txt := make(map[string]string, len(raw))
+	for _, seg := range raw {
+		key, value, _ := strings.Cut(seg, "=")
+		key = strings.ToLower(key)
+		if key == "" {
+			continue
+		}
+		if _, ok := txt[key]; ok {
+			continue
+		}
+		txt[key] = value
+	}
+	return txt
+}
+
+// complete reports whether enough records have been collected to hand
+// this entry to a caller: a target and port from SRV, at least one
+// address from A/AAAA, and the TXT record (even if empty).
+func (s *ServiceEntry) complete() bool {
+	return s.hasSRV && (s.AddrV4 != nil || s.AddrV6 != nil) && s.hasTXT
+}
+
+// equal reports whether s and o describe the same service data,
+// ignoring TTL, which Watch uses to tell an Updated event from a
+// harmless re-announcement of the same records.
+func (s *ServiceEntry) equal(o *ServiceEntry) bool {
+	if s.Host != o.Host || s.Port != o.Port {
+		return false
+	}
+	if !s.AddrV4.Equal(o.AddrV4) || !s.AddrV6.Equal(o.AddrV6) {
+		return false
+	}
+	if len(s.TXT) != len(o.TXT) {
+		return false
+	}
+	for k, v := range s.TXT {
+		if o.TXT[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// QueryParam configures a single Lookup.
+type QueryParam struct {
+	// Domain is the mDNS domain to query in, defaulting to "local." when empty.
+	Domain string
+
+	// Timeout bounds how long to wait for answers. Zero means wait until ctx is done.
+	Timeout time.Duration
+
+	// WantUnicastResponse asks responders to reply via unicast instead
+	// of multicast, per RFC 6762 Section 18.12.
+	WantUnicastResponse bool
+
+	// Interfaces restricts which network interfaces the query is sent
+	// on and listened for, defaulting to every multicast-capable one.
+	Interfaces Interfaces
+}
@@ -0,0 +1,49 @@
+package mdns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTXT(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  []string
+		want map[string]string
+	}{
+		{
+			name: "duplicate key first wins",
+			raw:  []string{"model=A", "model=B"},
+			want: map[string]string{"model": "A"},
+		},
+		{
+			name: "plain key=value",
+			raw:  []string{"paper=A4"},
+			want: map[string]string{"paper": "A4"},
+		},
+		{
+			name: "boolean key with no equals",
+			raw:  []string{"duplex"},
+			want: map[string]string{"duplex": ""},
+		},
+		{
+			name: "empty segment is skipped",
+			raw:  []string{""},
+			want: map[string]string{},
+		},
+		{
+			name: "mixed case key lowercased",
+			raw:  []string{"Model=A4", "MODEL=A3"},
+			want: map[string]string{"model": "A4"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := parseTXT(c.raw)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("parseTXT(%v) = %v, want %v", c.raw, got, c.want)
+			}
+		})
+	}
+}
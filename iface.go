@@ -0,0 +1,28 @@
+package mdns
+
+import "net"
+
+// interfaceSource overrides how mDNS enumerates network interfaces, for
+// platforms where net.Interfaces isn't reliable. It's nil by default;
+// android builds set it in iface_android.go, and any platform can opt
+// in via SetInterfaceSource.
+var interfaceSource func() ([]net.Interface, error)
+
+// SetInterfaceSource overrides how mDNS lists network interfaces. Use it
+// when embedding this package somewhere net.Interfaces() can't be
+// trusted, e.g. a gomobile binary running on Android, where it's known
+// to return an empty list (see golang/go#40569). Passing nil makes mDNS
+// call net.Interfaces() directly; on android builds that also drops the
+// fallback set in iface_android.go.
+func SetInterfaceSource(f func() ([]net.Interface, error)) {
+	interfaceSource = f
+}
+
+// listInterfaces returns the host's network interfaces, using
+// interfaceSource if one has been set.
+func listInterfaces() ([]net.Interface, error) {
+	if interfaceSource != nil {
+		return interfaceSource()
+	}
+	return net.Interfaces()
+}
@@ -0,0 +1,23 @@
+//go:build android
+
+package mdns
+
+import (
+	"net"
+
+	"github.com/wlynxg/anet"
+)
+
+func init() {
+	interfaceSource = androidInterfaces
+}
+
+// androidInterfaces falls back to anet's /proc/net and netlink based
+// enumeration when net.Interfaces returns nothing, which it reliably
+// does inside an Android app sandbox (see golang/go#40569).
+func androidInterfaces() ([]net.Interface, error) {
+	if ifaces, err := net.Interfaces(); err == nil && len(ifaces) > 0 {
+		return ifaces, nil
+	}
+	return anet.Interfaces()
+}
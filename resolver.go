@@ -0,0 +1,263 @@
+// Package mdns implements client and server support for multicast DNS
+// service discovery.
+//
+// https://datatracker.ietf.org/doc/html/rfc6762
+// https://datatracker.ietf.org/doc/html/rfc6763
+package mdns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	mdnsAddr4 = net.UDPAddrFromAddrPort(netip.MustParseAddrPort("224.0.0.251:5353"))
+	mdnsAddr6 = net.UDPAddrFromAddrPort(netip.MustParseAddrPort("[ff02::fb]:5353"))
+)
+
+// servicesMetaQuery is the well-known name used to enumerate service
+// types present on the network, per RFC 6763 Section 9.
+const servicesMetaQuery = "_services._dns-sd._udp.local."
+
+// Resolver queries for mDNS services on the local network.
+type Resolver struct{}
+
+// NewResolver creates a Resolver ready to Browse, Lookup, or Scan.
+func NewResolver() (*Resolver, error) {
+	return &Resolver{}, nil
+}
+
+// Lookup queries for a specific service type (e.g. "_http._tcp") in the
+// given domain and streams ServiceEntry results as they complete. The
+// returned channel is closed when ctx is done.
+func (r *Resolver) Lookup(ctx context.Context, service string, params QueryParam) (<-chan *ServiceEntry, error) {
+	domain := params.Domain
+	if domain == "" {
+		domain = "local."
+	}
+	name := dns.Fqdn(service) + domain
+
+	cancel := func() {}
+	if params.Timeout != 0 {
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+	}
+
+	c, err := listen(params.Interfaces)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		c.Close()
+		cancel()
+	}()
+
+	if err := sendQuery(c, name, params.WantUnicastResponse); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	out := make(chan *ServiceEntry)
+	go collect(c, out, nil)
+	return out, nil
+}
+
+// Browse is a convenience wrapper around Lookup for the common case of
+// browsing a service type in the "local." domain.
+func (r *Resolver) Browse(ctx context.Context, service string) (<-chan *ServiceEntry, error) {
+	return r.Lookup(ctx, service, QueryParam{})
+}
+
+// Scan enumerates every service type advertised on the network and
+// streams a ServiceEntry for each instance found, resolving PTR answers
+// to SRV/A/AAAA/TXT as they arrive. It stops after timeout, or runs
+// until ctx is done if timeout is zero.
+func (r *Resolver) Scan(ctx context.Context, timeout time.Duration) (<-chan *ServiceEntry, error) {
+	cancel := func() {}
+	if timeout != 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	c, err := listen(Interfaces{})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		c.Close()
+		cancel()
+	}()
+
+	if err := sendQuery(c, servicesMetaQuery, true); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	services := map[string]struct{}{}
+	onAnswer := func(msg *dns.Msg) bool {
+		if len(msg.Question) == 0 || msg.Question[0].Name != servicesMetaQuery {
+			return false
+		}
+		for _, a := range msg.Answer {
+			ptr, ok := a.(*dns.PTR)
+			if !ok {
+				continue
+			}
+			if _, ok := services[ptr.Ptr]; ok {
+				continue
+			}
+			services[ptr.Ptr] = struct{}{}
+			sendQuery(c, ptr.Ptr, true)
+		}
+		return true
+	}
+
+	out := make(chan *ServiceEntry)
+	go collect(c, out, onAnswer)
+	return out, nil
+}
+
+// entryCache assembles ServiceEntry values out of resource records that
+// may arrive across several packets: SRV/TXT/PTR are keyed by instance
+// name, while A/AAAA are keyed by the hostname they describe and are
+// applied to any entry whose SRV target matches once it turns up.
+type entryCache struct {
+	entries    map[string]*ServiceEntry
+	hostAddrV4 map[string]net.IP
+	hostAddrV6 map[string]net.IP
+}
+
+func newEntryCache() *entryCache {
+	return &entryCache{
+		entries:    map[string]*ServiceEntry{},
+		hostAddrV4: map[string]net.IP{},
+		hostAddrV6: map[string]net.IP{},
+	}
+}
+
+// apply folds rrs into the cache and returns the entries touched by this
+// call, so the caller can check which ones just became complete.
+func (c *entryCache) apply(rrs []dns.RR) []*ServiceEntry {
+	var touched []*ServiceEntry
+	entryFor := func(name string) *ServiceEntry {
+		e, ok := c.entries[name]
+		if !ok {
+			e = &ServiceEntry{Name: name}
+			c.entries[name] = e
+		}
+		return e
+	}
+
+	for _, rr := range rrs {
+		switch rr := rr.(type) {
+		case *dns.PTR:
+			touched = append(touched, entryFor(rr.Ptr))
+		case *dns.SRV:
+			e := entryFor(rr.Hdr.Name)
+			e.Host = strings.TrimSuffix(rr.Target, ".")
+			e.Port = int(rr.Port)
+			e.TTL = rr.Hdr.Ttl
+			e.hasSRV = true
+			if addr, ok := c.hostAddrV4[e.Host]; ok {
+				e.AddrV4 = addr
+			}
+			if addr, ok := c.hostAddrV6[e.Host]; ok {
+				e.AddrV6 = addr
+			}
+			touched = append(touched, e)
+		case *dns.TXT:
+			e := entryFor(rr.Hdr.Name)
+			e.TXTRaw = rr.Txt
+			e.TXT = parseTXT(rr.Txt)
+			e.hasTXT = true
+			touched = append(touched, e)
+		case *dns.A:
+			host := strings.TrimSuffix(rr.Hdr.Name, ".")
+			c.hostAddrV4[host] = rr.A
+			for _, e := range c.entries {
+				if e.Host == host {
+					e.AddrV4 = rr.A
+					touched = append(touched, e)
+				}
+			}
+		case *dns.AAAA:
+			host := strings.TrimSuffix(rr.Hdr.Name, ".")
+			c.hostAddrV6[host] = rr.AAAA
+			for _, e := range c.entries {
+				if e.Host == host {
+					e.AddrV6 = rr.AAAA
+					touched = append(touched, e)
+				}
+			}
+		}
+	}
+	return touched
+}
+
+// collect reads mDNS responses off c until it's closed, folding records
+// into an entryCache and sending newly-completed entries to out.
+// onAnswer, if non-nil, is given each message first and may consume it
+// (returning true) to drive query fan-out instead of entry assembly.
+func collect(c *conn, out chan<- *ServiceEntry, onAnswer func(*dns.Msg) bool) {
+	defer close(out)
+
+	cache := newEntryCache()
+	sent := map[string]bool{}
+
+	for {
+		data, _, err := c.ReadFrom()
+		if err != nil {
+			return
+		}
+		msg := &dns.Msg{}
+		if err := msg.Unpack(data); err != nil {
+			continue
+		}
+
+		if onAnswer != nil && onAnswer(msg) {
+			continue
+		}
+
+		for _, e := range cache.apply(append(msg.Answer, msg.Extra...)) {
+			if e.complete() && !sent[e.Name] {
+				sent[e.Name] = true
+				out <- e
+			}
+		}
+	}
+}
+
+// sendQuery packs and sends a single PTR question for name out every
+// joined interface, on both IPv4 and IPv6.
+func sendQuery(c *conn, name string, wantUnicast bool) error {
+	return sendQueryType(c, name, dns.TypePTR, wantUnicast)
+}
+
+// sendQueryType packs and sends a single question of type qtype for
+// name out every joined interface, on both IPv4 and IPv6.
+func sendQueryType(c *conn, name string, qtype uint16, wantUnicast bool) error {
+	var class uint16 = dns.ClassINET
+	if wantUnicast {
+		class |= 1 << 15
+	}
+	m := &dns.Msg{
+		MsgHdr: dns.MsgHdr{Id: dns.Id()},
+		Question: []dns.Question{{
+			Name:   name,
+			Qtype:  qtype,
+			Qclass: class,
+		}},
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	return c.WriteTo(buf, nil)
+}
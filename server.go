@@ -0,0 +1,349 @@
+package mdns
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// probeInterval is the spacing between probe queries during startup, per
+// RFC 6762 Section 8.1.
+const probeInterval = 250 * time.Millisecond
+
+// Server advertises the services in a Zone and answers PTR/SRV/TXT/A/AAAA
+// queries for them, including the "_services._dns-sd._udp.local." meta
+// query used for service enumeration (RFC 6763 Section 9).
+type Server struct {
+	zone Zone
+
+	// Interfaces restricts which network interfaces the server listens
+	// and advertises on, defaulting to every multicast-capable one.
+	Interfaces Interfaces
+
+	mu      sync.Mutex
+	conn    *conn
+	done    chan struct{}
+	claimed map[string]string // svc's pre-probe instance name -> name actually claimed, if renamed on conflict
+}
+
+// NewServer creates a Server advertising zone. Call Start to begin
+// probing and answering queries.
+func NewServer(zone Zone) (*Server, error) {
+	return &Server{zone: zone}, nil
+}
+
+// Start probes for name conflicts on every service in the zone and, once
+// each name is claimed, begins answering queries. It returns once
+// probing has completed.
+func (s *Server) Start() error {
+	c, err := listen(s.Interfaces)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.conn = c
+	s.done = make(chan struct{})
+	s.claimed = map[string]string{}
+	s.mu.Unlock()
+
+	for _, svc := range s.zone.Services() {
+		if err := s.probe(svc); err != nil {
+			c.Close()
+			return err
+		}
+	}
+
+	go s.serve()
+	return nil
+}
+
+// Stop sends goodbye packets (TTL=0) for every advertised service and
+// stops answering queries.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	c := s.conn
+	done := s.done
+	s.mu.Unlock()
+	if c == nil {
+		return nil
+	}
+
+	for _, svc := range s.zone.Services() {
+		rrs := s.records(svc, 0)
+		m := &dns.Msg{MsgHdr: dns.MsgHdr{Response: true, Authoritative: true}, Answer: rrs}
+		buf, err := m.Pack()
+		if err != nil {
+			continue
+		}
+		c.WriteTo(buf, nil)
+	}
+
+	close(done)
+	return c.Close()
+}
+
+// probe claims svc's instance name, sending three probe queries 250ms
+// apart and renaming on conflict, per RFC 6762 Section 8.1-8.2. It
+// doesn't mutate svc; the claimed name (which may differ from
+// svc.Instance if it had to be renamed) is recorded in s.claimed, keyed
+// by svc's pre-probe instance name rather than the *Service pointer, so
+// a Zone backed by a dynamic registry that hands back a fresh *Service
+// value each call to Services() still finds its claimed rename.
+func (s *Server) probe(svc *Service) error {
+	original := svc.instanceName()
+	suffix := 1
+	for {
+		name := original
+		if suffix > 1 {
+			name = svc.Instance + " (" + strconv.Itoa(suffix) + ")." + svc.serviceName()
+		}
+		conflict, err := s.probeOnce(name)
+		if err != nil {
+			return err
+		}
+		if !conflict {
+			if suffix > 1 {
+				s.mu.Lock()
+				s.claimed[original] = name
+				s.mu.Unlock()
+			}
+			return nil
+		}
+		suffix++
+	}
+}
+
+// probeOnce sends the three probe queries for name and reports whether
+// a conflicting answer was seen.
+func (s *Server) probeOnce(name string) (conflict bool, err error) {
+	s.mu.Lock()
+	c := s.conn
+	s.mu.Unlock()
+
+	q := dns.Msg{
+		MsgHdr:   dns.MsgHdr{Id: dns.Id()},
+		Question: []dns.Question{{Name: name, Qtype: dns.TypeANY, Qclass: dns.ClassINET}},
+	}
+	buf, err := q.Pack()
+	if err != nil {
+		return false, err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := c.WriteTo(buf, nil); err != nil {
+			return false, err
+		}
+		deadline := time.After(probeInterval)
+	wait:
+		for {
+			select {
+			case r := <-c.in:
+				msg := &dns.Msg{}
+				if err := msg.Unpack(r.data); err == nil && answersConflictWith(msg, name) {
+					return true, nil
+				}
+			case <-deadline:
+				break wait
+			}
+		}
+	}
+	return false, nil
+}
+
+// answersConflictWith reports whether msg carries any record for name in
+// its Answer or Authority section, which probe takes to mean another
+// host already owns it. The record's data isn't compared with what we'd
+// advertise ourselves.
+func answersConflictWith(msg *dns.Msg, name string) bool {
+	name = wireName(name)
+	for _, rr := range append(msg.Answer, msg.Ns...) {
+		if strings.EqualFold(rr.Header().Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// wireName renders name the way it comes back out after being read off
+// the wire, so it can be compared against a name parsed from an
+// incoming packet. miekg/dns escapes bytes like spaces and parentheses
+// (common in human-chosen Instance names, and in the "(2)" suffix probe
+// adds on conflict) when unpacking a domain name, so comparing an
+// unescaped, locally-built name straight against one off the wire never
+// matches.
+func wireName(name string) string {
+	buf := make([]byte, 255)
+	off, err := dns.PackDomainName(name, buf, 0, nil, false)
+	if err != nil {
+		return name
+	}
+	s, _, err := dns.UnpackDomainName(buf[:off], 0)
+	if err != nil {
+		return name
+	}
+	return s
+}
+
+// instanceName returns the instance name svc is actually advertised
+// under, accounting for any rename probe forced to resolve a conflict.
+func (s *Server) instanceName(svc *Service) string {
+	original := svc.instanceName()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name, ok := s.claimed[original]; ok {
+		return name
+	}
+	return original
+}
+
+// serve answers incoming queries until Stop is called.
+func (s *Server) serve() {
+	s.mu.Lock()
+	c := s.conn
+	done := s.done
+	s.mu.Unlock()
+
+	for {
+		data, addr, err := c.ReadFrom()
+		if err != nil {
+			return
+		}
+		msg := &dns.Msg{}
+		if err := msg.Unpack(data); err != nil {
+			continue
+		}
+		if msg.Response || len(msg.Question) == 0 {
+			continue
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+		s.answer(c, addr, msg)
+	}
+}
+
+// answer builds and sends a response to a single incoming query.
+func (s *Server) answer(c *conn, addr net.Addr, msg *dns.Msg) {
+	q := msg.Question[0]
+
+	if q.Name == servicesMetaQuery && q.Qtype == dns.TypePTR {
+		seen := map[string]bool{}
+		var answers []dns.RR
+		for _, svc := range s.zone.Services() {
+			name := svc.serviceName()
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			answers = append(answers, &dns.PTR{
+				Hdr: dns.RR_Header{Name: servicesMetaQuery, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 4500},
+				Ptr: name,
+			})
+		}
+		if len(answers) > 0 {
+			s.respond(c, addr, msg, answers)
+		}
+		return
+	}
+
+	for _, svc := range s.zone.Services() {
+		instance := wireName(s.instanceName(svc))
+		host := instance
+		if svc.Host != "" {
+			host = wireName(svc.Host)
+		}
+		if (q.Qtype == dns.TypeA || q.Qtype == dns.TypeAAAA) && strings.EqualFold(q.Name, host) {
+			s.respond(c, addr, msg, s.records(svc, 120))
+			continue
+		}
+		if !strings.EqualFold(q.Name, wireName(svc.serviceName())) && !strings.EqualFold(q.Name, instance) {
+			continue
+		}
+		switch q.Qtype {
+		case dns.TypePTR, dns.TypeANY:
+			s.respond(c, addr, msg, s.records(svc, 120))
+		case dns.TypeSRV, dns.TypeTXT, dns.TypeA, dns.TypeAAAA:
+			if strings.EqualFold(q.Name, instance) {
+				s.respond(c, addr, msg, s.records(svc, 120))
+			}
+		}
+	}
+}
+
+// records builds the full record set for svc: PTR, SRV, TXT, and
+// address records. respond puts the PTR in the answer and the rest in
+// additional data, mirroring how real mDNS responders pack these
+// together so clients can resolve an instance from a single packet.
+func (s *Server) records(svc *Service, ttl uint32) []dns.RR {
+	instance := s.instanceName(svc)
+	service := svc.serviceName()
+	host := svc.Host
+	if host == "" {
+		host = instance
+	}
+
+	rrs := []dns.RR{
+		&dns.PTR{
+			Hdr: dns.RR_Header{Name: service, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: ttl},
+			Ptr: instance,
+		},
+		&dns.SRV{
+			Hdr:      dns.RR_Header{Name: instance, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: ttl},
+			Priority: 0,
+			Weight:   0,
+			Port:     uint16(svc.Port),
+			Target:   host,
+		},
+		&dns.TXT{
+			Hdr: dns.RR_Header{Name: instance, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl},
+			Txt: svc.TXT,
+		},
+	}
+	if svc.AddrV4 != nil {
+		rrs = append(rrs, &dns.A{
+			Hdr: dns.RR_Header{Name: host, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+			A:   svc.AddrV4,
+		})
+	}
+	if svc.AddrV6 != nil {
+		rrs = append(rrs, &dns.AAAA{
+			Hdr:  dns.RR_Header{Name: host, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl},
+			AAAA: svc.AddrV6,
+		})
+	}
+	return rrs
+}
+
+// respond answers a query, putting the PTR record in Answer and the
+// rest in Extra, and sends it unicast or multicast depending on what
+// the querier asked for.
+func (s *Server) respond(c *conn, addr net.Addr, query *dns.Msg, rrs []dns.RR) {
+	m := &dns.Msg{
+		MsgHdr:   dns.MsgHdr{Id: query.Id, Response: true, Authoritative: true},
+		Question: query.Question,
+	}
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypePTR {
+			m.Answer = append(m.Answer, rr)
+		} else {
+			m.Extra = append(m.Extra, rr)
+		}
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		return
+	}
+
+	var dst net.Addr
+	if len(query.Question) > 0 && query.Question[0].Qclass&(1<<15) != 0 {
+		dst = addr
+	}
+	c.WriteTo(buf, dst)
+}
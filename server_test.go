@@ -0,0 +1,128 @@
+package mdns
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func packConflict(t *testing.T, name string) []byte {
+	t.Helper()
+	m := &dns.Msg{
+		MsgHdr: dns.MsgHdr{Response: true},
+		Answer: []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 120},
+		}},
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+	return buf
+}
+
+func TestAnswersConflictWith(t *testing.T) {
+	name := "Printer._ipp._tcp.local."
+
+	cases := []struct {
+		name string
+		msg  *dns.Msg
+		want bool
+	}{
+		{
+			name: "matching answer",
+			msg:  &dns.Msg{Answer: []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: name}}}},
+			want: true,
+		},
+		{
+			name: "matching authority",
+			msg:  &dns.Msg{Ns: []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: name}}}},
+			want: true,
+		},
+		{
+			name: "case-insensitive match",
+			msg:  &dns.Msg{Answer: []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "PRINTER._IPP._TCP.LOCAL."}}}},
+			want: true,
+		},
+		{
+			name: "no match",
+			msg:  &dns.Msg{Answer: []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "Other._ipp._tcp.local."}}}},
+			want: false,
+		},
+		{
+			name: "empty message",
+			msg:  &dns.Msg{},
+			want: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := answersConflictWith(c.msg, name); got != c.want {
+				t.Errorf("answersConflictWith() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// TestProbeRename verifies that a probe conflict renames the claimed
+// instance with an incrementing " (n)" suffix without mutating the
+// original Service, and that the final claimed name is tracked by the
+// service's pre-probe instance name in s.claimed.
+func TestProbeRename(t *testing.T) {
+	svc := &Service{Instance: "Printer", Service: "_ipp._tcp", Port: 631}
+	s := &Server{
+		conn:    &conn{in: make(chan received, 8), closed: make(chan struct{})},
+		claimed: map[string]string{},
+	}
+
+	name1 := svc.instanceName()
+	name2 := "Printer (2)." + svc.serviceName()
+	s.conn.in <- received{data: packConflict(t, name1)}
+	s.conn.in <- received{data: packConflict(t, name2)}
+
+	if err := s.probe(svc); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+
+	if svc.Instance != "Printer" {
+		t.Errorf("probe mutated svc.Instance to %q", svc.Instance)
+	}
+
+	want := "Printer (3)." + svc.serviceName()
+	if got := s.instanceName(svc); got != want {
+		t.Errorf("instanceName() = %q, want %q", got, want)
+	}
+}
+
+// TestInstanceNameStableAcrossServiceValues verifies that a rename
+// claimed for one *Service value is found by instanceName() given a
+// distinct *Service with the same Instance/Service/Domain, which is how
+// a Zone backed by a dynamic registry hands back services: a fresh
+// *Service per call to Services().
+func TestInstanceNameStableAcrossServiceValues(t *testing.T) {
+	svc := &Service{Instance: "Printer", Service: "_ipp._tcp", Port: 631}
+	s := &Server{claimed: map[string]string{
+		svc.instanceName(): "Printer (2)." + svc.serviceName(),
+	}}
+
+	other := &Service{Instance: "Printer", Service: "_ipp._tcp", Port: 631}
+	want := "Printer (2)." + other.serviceName()
+	if got := s.instanceName(other); got != want {
+		t.Errorf("instanceName() on a distinct *Service = %q, want %q", got, want)
+	}
+}
+
+// TestProbeOnceNoConflict verifies that a clean probe (no conflicting
+// answers seen) reports no conflict once all three probes are sent.
+func TestProbeOnceNoConflict(t *testing.T) {
+	s := &Server{conn: &conn{in: make(chan received), closed: make(chan struct{})}}
+
+	conflict, err := s.probeOnce("Printer._ipp._tcp.local.")
+	if err != nil {
+		t.Fatalf("probeOnce: %v", err)
+	}
+	if conflict {
+		t.Fatal("probeOnce reported a conflict with no competing answers")
+	}
+}
@@ -0,0 +1,237 @@
+package mdns
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Interfaces restricts which network interfaces mDNS uses. The zero
+// value uses every multicast-capable interface, which is the right
+// choice for most hosts; Only/Except let callers narrow that down on
+// multi-homed hosts.
+type Interfaces struct {
+	// Only, if non-empty, restricts mDNS to these interface names.
+	Only []string
+	// Except excludes these interface names even if they'd otherwise qualify.
+	Except []string
+}
+
+func (f Interfaces) allows(name string) bool {
+	if len(f.Only) > 0 && !containsString(f.Only, name) {
+		return false
+	}
+	return !containsString(f.Except, name)
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// multicastInterfaces returns the up, multicast-capable interfaces that
+// filter allows.
+func multicastInterfaces(filter Interfaces) ([]net.Interface, error) {
+	ifaces, err := listInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	var out []net.Interface
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		if !filter.allows(ifi.Name) {
+			continue
+		}
+		out = append(out, ifi)
+	}
+	return out, nil
+}
+
+// received is a single packet read off either the IPv4 or IPv6 socket.
+type received struct {
+	data []byte
+	addr net.Addr
+}
+
+// conn fans queries and responses out across every joined interface on
+// both IPv4 and IPv6, and fans incoming packets in to a single stream.
+type conn struct {
+	pc4    *ipv4.PacketConn
+	pc6    *ipv6.PacketConn
+	ifaces []net.Interface
+
+	in     chan received
+	closed chan struct{}
+	once   sync.Once
+}
+
+// listen opens a multicast mDNS transport, joining mdnsAddr4 and
+// mdnsAddr6 on every interface filter allows.
+func listen(filter Interfaces) (*conn, error) {
+	ifaces, err := multicastInterfaces(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &conn{ifaces: ifaces, in: make(chan received, 32), closed: make(chan struct{})}
+
+	if uc4, err := listenReusable("udp4", ":5353"); err == nil {
+		pc4 := ipv4.NewPacketConn(uc4)
+		var joined bool
+		for _, ifi := range ifaces {
+			if err := pc4.JoinGroup(&ifi, mdnsAddr4); err == nil {
+				joined = true
+			}
+		}
+		if joined {
+			pc4.SetMulticastLoopback(true)
+			c.pc4 = pc4
+		} else {
+			uc4.Close()
+		}
+	}
+
+	if uc6, err := listenReusable("udp6", ":5353"); err == nil {
+		pc6 := ipv6.NewPacketConn(uc6)
+		var joined bool
+		for _, ifi := range ifaces {
+			if err := pc6.JoinGroup(&ifi, mdnsAddr6); err == nil {
+				joined = true
+			}
+		}
+		if joined {
+			pc6.SetMulticastLoopback(true)
+			c.pc6 = pc6
+		} else {
+			uc6.Close()
+		}
+	}
+
+	if c.pc4 == nil && c.pc6 == nil {
+		return nil, errors.New("mdns: no usable multicast interface")
+	}
+
+	if c.pc4 != nil {
+		go c.readLoop4()
+	}
+	if c.pc6 != nil {
+		go c.readLoop6()
+	}
+	return c, nil
+}
+
+func (c *conn) readLoop4() {
+	buf := make([]byte, 0xffff)
+	for {
+		n, _, addr, err := c.pc4.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		c.deliver(buf[:n], addr)
+	}
+}
+
+func (c *conn) readLoop6() {
+	buf := make([]byte, 0xffff)
+	for {
+		n, _, addr, err := c.pc6.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		c.deliver(buf[:n], addr)
+	}
+}
+
+func (c *conn) deliver(data []byte, addr net.Addr) {
+	msg := make([]byte, len(data))
+	copy(msg, data)
+	select {
+	case c.in <- received{data: msg, addr: addr}:
+	case <-c.closed:
+	}
+}
+
+// ReadFrom returns the next packet received on any joined interface or
+// address family. It returns net.ErrClosed once the conn is closed.
+func (c *conn) ReadFrom() ([]byte, net.Addr, error) {
+	select {
+	case r := <-c.in:
+		return r.data, r.addr, nil
+	case <-c.closed:
+		return nil, nil, net.ErrClosed
+	}
+}
+
+// WriteTo sends buf to dst. If dst is a specific unicast address, it's
+// sent once on whichever address family matches; if dst is nil or a
+// multicast group address, it's sent out every joined interface on both
+// address families, which is how queries and multicast responses go out.
+func (c *conn) WriteTo(buf []byte, dst net.Addr) error {
+	if dst != nil {
+		if udp, ok := dst.(*net.UDPAddr); ok && !udp.IP.IsMulticast() {
+			if udp.IP.To4() != nil {
+				if c.pc4 == nil {
+					return errors.New("mdns: no ipv4 socket")
+				}
+				_, err := c.pc4.WriteTo(buf, nil, dst)
+				return err
+			}
+			if c.pc6 == nil {
+				return errors.New("mdns: no ipv6 socket")
+			}
+			_, err := c.pc6.WriteTo(buf, nil, dst)
+			return err
+		}
+	}
+
+	var firstErr error
+	if c.pc4 != nil {
+		for _, ifi := range c.ifaces {
+			ifi := ifi
+			if err := c.pc4.SetMulticastInterface(&ifi); err != nil {
+				continue
+			}
+			if _, err := c.pc4.WriteTo(buf, nil, mdnsAddr4); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if c.pc6 != nil {
+		for _, ifi := range c.ifaces {
+			ifi := ifi
+			if err := c.pc6.SetMulticastInterface(&ifi); err != nil {
+				continue
+			}
+			if _, err := c.pc6.WriteTo(buf, nil, mdnsAddr6); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Close stops all read loops and closes both sockets.
+func (c *conn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	var err error
+	if c.pc4 != nil {
+		if e := c.pc4.Close(); e != nil {
+			err = e
+		}
+	}
+	if c.pc6 != nil {
+		if e := c.pc6.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
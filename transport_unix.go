@@ -0,0 +1,27 @@
+//go:build !windows
+
+package mdns
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+// listenReusable opens a UDP socket with SO_REUSEADDR set, so the
+// resolver and a Server can both bind :5353 on the same host.
+func listenReusable(network, address string) (net.PacketConn, error) {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	return lc.ListenPacket(context.Background(), network, address)
+}
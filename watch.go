@@ -0,0 +1,167 @@
+package mdns
+
+import (
+	"context"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// EventType describes how a Watch event changes the cache.
+type EventType int
+
+const (
+	Added EventType = iota
+	Updated
+	Removed
+)
+
+// Event reports a change to a cached service discovered by Watch.
+type Event struct {
+	Type  EventType
+	Entry *ServiceEntry
+}
+
+const (
+	minRequeryInterval = 1 * time.Second
+	maxRequeryInterval = 60 * time.Second
+)
+
+// refreshFractions are the points in a record's TTL, per RFC 6762
+// Section 5.2, at which Watch proactively re-queries it before it
+// expires, so a live service's entry never actually lapses.
+var refreshFractions = []float64{0.80, 0.85, 0.90, 0.95}
+
+// sendRefreshQuery issues a refresh query for an instance; swapped out
+// in tests to observe refresh queries without a real socket.
+var sendRefreshQuery = sendQueryType
+
+// watchedEntry tracks one cached ServiceEntry's TTL-driven lifecycle.
+type watchedEntry struct {
+	entry     *ServiceEntry
+	expiresAt time.Time
+	refreshAt []time.Time // remaining refresh times, ascending
+}
+
+// Watch maintains a live cache of instances of service and streams
+// Added, Updated, and Removed events as it changes. It re-issues the
+// PTR query with exponential backoff from 1s up to 60s, refreshes each
+// record ahead of its TTL expiring, and evicts entries whose TTL lapses
+// or that sent a goodbye (TTL=0). It runs until ctx is done.
+func (r *Resolver) Watch(ctx context.Context, service string) (<-chan Event, error) {
+	name := dns.Fqdn(service) + "local."
+
+	c, err := listen(Interfaces{})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		c.Close()
+	}()
+
+	if err := sendQuery(c, name, false); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	out := make(chan Event)
+	go watch(c, name, out)
+	return out, nil
+}
+
+func watch(c *conn, name string, out chan<- Event) {
+	defer close(out)
+
+	cache := map[string]*watchedEntry{}
+	raw := newEntryCache()
+
+	interval := minRequeryInterval
+	requery := time.NewTimer(interval)
+	defer requery.Stop()
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		select {
+		case r, ok := <-c.in:
+			if !ok {
+				return
+			}
+			msg := &dns.Msg{}
+			if err := msg.Unpack(r.data); err != nil {
+				continue
+			}
+			for _, e := range raw.apply(append(msg.Answer, msg.Extra...)) {
+				applyWatchEntry(cache, e, out)
+			}
+		case <-requery.C:
+			sendQuery(c, name, false)
+			interval *= 2
+			if interval > maxRequeryInterval {
+				interval = maxRequeryInterval
+			}
+			requery.Reset(interval)
+		case now := <-tick.C:
+			expireAndRefresh(c, cache, now, out)
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// applyWatchEntry folds a newly-touched entry into the cache, emitting
+// Added, Updated, or Removed as appropriate.
+func applyWatchEntry(cache map[string]*watchedEntry, e *ServiceEntry, out chan<- Event) {
+	if !e.complete() {
+		return
+	}
+	// entryCache.apply hands back the same *ServiceEntry it keeps mutating,
+	// so cache and emit a snapshot for equal to compare against later.
+	snap := *e
+	e = &snap
+
+	w, existed := cache[e.Name]
+	if e.TTL == 0 {
+		if existed {
+			delete(cache, e.Name)
+			out <- Event{Type: Removed, Entry: e}
+		}
+		return
+	}
+
+	now := time.Now()
+	ttl := time.Duration(e.TTL) * time.Second
+	refreshAt := make([]time.Time, len(refreshFractions))
+	for i, f := range refreshFractions {
+		refreshAt[i] = now.Add(time.Duration(float64(ttl) * f))
+	}
+	cache[e.Name] = &watchedEntry{entry: e, expiresAt: now.Add(ttl), refreshAt: refreshAt}
+
+	switch {
+	case !existed:
+		out <- Event{Type: Added, Entry: e}
+	case !w.entry.equal(e):
+		out <- Event{Type: Updated, Entry: e}
+	}
+}
+
+// expireAndRefresh evicts entries whose TTL has lapsed and re-queries
+// ones due for a proactive refresh. Refresh queries target the
+// instance's own records (ANY), not the service's PTR: no responder
+// publishes a PTR at the instance name, so a PTR question here would
+// never be answered and the entry would lapse anyway.
+func expireAndRefresh(c *conn, cache map[string]*watchedEntry, now time.Time, out chan<- Event) {
+	for key, w := range cache {
+		if now.After(w.expiresAt) {
+			delete(cache, key)
+			out <- Event{Type: Removed, Entry: w.entry}
+			continue
+		}
+		for len(w.refreshAt) > 0 && now.After(w.refreshAt[0]) {
+			w.refreshAt = w.refreshAt[1:]
+			sendRefreshQuery(c, key, dns.TypeANY, false)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package mdns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func completeEntry(name string, ttl uint32, port int) *ServiceEntry {
+	return &ServiceEntry{
+		Name:   name,
+		Host:   "host.local.",
+		Port:   port,
+		AddrV4: net.IPv4(192, 168, 1, 1),
+		TTL:    ttl,
+		TXT:    map[string]string{},
+		hasSRV: true,
+		hasTXT: true,
+	}
+}
+
+func TestApplyWatchEntryAddedUpdatedRemoved(t *testing.T) {
+	cache := map[string]*watchedEntry{}
+	name := "Printer._ipp._tcp.local."
+
+	out := make(chan Event, 1)
+	applyWatchEntry(cache, completeEntry(name, 120, 631), out)
+	select {
+	case e := <-out:
+		if e.Type != Added {
+			t.Fatalf("first entry: got %v, want Added", e.Type)
+		}
+	default:
+		t.Fatal("first entry: expected an event, got none")
+	}
+	if _, ok := cache[name]; !ok {
+		t.Fatal("entry not cached after Added")
+	}
+
+	// An identical re-announcement (e.g. a refresh response) must not
+	// fire an event.
+	applyWatchEntry(cache, completeEntry(name, 120, 631), out)
+	select {
+	case e := <-out:
+		t.Fatalf("identical re-announcement fired %v, want no event", e.Type)
+	default:
+	}
+
+	// A changed record fires Updated.
+	applyWatchEntry(cache, completeEntry(name, 120, 632), out)
+	select {
+	case e := <-out:
+		if e.Type != Updated {
+			t.Fatalf("changed entry: got %v, want Updated", e.Type)
+		}
+	default:
+		t.Fatal("changed entry: expected an event, got none")
+	}
+
+	// A goodbye (TTL=0) evicts the entry and fires Removed.
+	applyWatchEntry(cache, completeEntry(name, 0, 632), out)
+	select {
+	case e := <-out:
+		if e.Type != Removed {
+			t.Fatalf("goodbye: got %v, want Removed", e.Type)
+		}
+	default:
+		t.Fatal("goodbye: expected an event, got none")
+	}
+	if _, ok := cache[name]; ok {
+		t.Fatal("entry still cached after goodbye")
+	}
+}
+
+func TestApplyWatchEntryIncompleteIgnored(t *testing.T) {
+	cache := map[string]*watchedEntry{}
+	out := make(chan Event, 1)
+	applyWatchEntry(cache, &ServiceEntry{Name: "Printer._ipp._tcp.local."}, out)
+	select {
+	case e := <-out:
+		t.Fatalf("incomplete entry fired %v, want no event", e.Type)
+	default:
+	}
+	if len(cache) != 0 {
+		t.Fatal("incomplete entry was cached")
+	}
+}
+
+func TestExpireAndRefreshExpiry(t *testing.T) {
+	c := &conn{in: make(chan received), closed: make(chan struct{})}
+	name := "Printer._ipp._tcp.local."
+	entry := completeEntry(name, 120, 631)
+	cache := map[string]*watchedEntry{
+		name: {entry: entry, expiresAt: time.Now().Add(-time.Second)},
+	}
+
+	out := make(chan Event, 1)
+	expireAndRefresh(c, cache, time.Now(), out)
+
+	select {
+	case e := <-out:
+		if e.Type != Removed || e.Entry != entry {
+			t.Fatalf("got %+v, want Removed event for expired entry", e)
+		}
+	default:
+		t.Fatal("expected a Removed event, got none")
+	}
+	if _, ok := cache[name]; ok {
+		t.Fatal("expired entry still cached")
+	}
+}
+
+func TestExpireAndRefreshSchedule(t *testing.T) {
+	orig := sendRefreshQuery
+	defer func() { sendRefreshQuery = orig }()
+
+	var gotName string
+	var gotType uint16
+	calls := 0
+	sendRefreshQuery = func(c *conn, name string, qtype uint16, wantUnicast bool) error {
+		calls++
+		gotName, gotType = name, qtype
+		return nil
+	}
+
+	c := &conn{in: make(chan received), closed: make(chan struct{})}
+	name := "Printer._ipp._tcp.local."
+	now := time.Now()
+	due := now.Add(-time.Second)
+	notYetDue := now.Add(time.Hour)
+	cache := map[string]*watchedEntry{
+		name: {
+			entry:     completeEntry(name, 120, 631),
+			expiresAt: now.Add(time.Minute),
+			refreshAt: []time.Time{due, notYetDue},
+		},
+	}
+
+	out := make(chan Event, 1)
+	expireAndRefresh(c, cache, now, out)
+
+	if calls != 1 {
+		t.Fatalf("sendRefreshQuery called %d times, want 1", calls)
+	}
+	if gotName != name {
+		t.Errorf("refresh query targeted %q, want %q", gotName, name)
+	}
+	if gotType != dns.TypeANY {
+		t.Errorf("refresh query type = %d, want dns.TypeANY (instance records, not PTR)", gotType)
+	}
+	if got := len(cache[name].refreshAt); got != 1 {
+		t.Fatalf("refreshAt has %d entries left, want 1 (only the due one consumed)", got)
+	}
+	if !cache[name].refreshAt[0].Equal(notYetDue) {
+		t.Fatalf("remaining refreshAt = %v, want %v", cache[name].refreshAt[0], notYetDue)
+	}
+}
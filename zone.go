@@ -0,0 +1,52 @@
+package mdns
+
+import "net"
+
+// Service describes a single service instance to advertise, named per
+// RFC 6763: "<Instance>.<Service>.<Domain>", e.g.
+// "My Printer._ipp._tcp.local.".
+type Service struct {
+	Instance string // e.g. "My Printer"
+	Service  string // e.g. "_ipp._tcp"
+	Domain   string // defaults to "local." when empty
+	Host     string // SRV target, e.g. "myhost.local."; defaults to the instance name
+	Port     int
+	TXT      []string
+
+	AddrV4 net.IP
+	AddrV6 net.IP
+}
+
+// domain returns s.Domain, defaulting to "local.".
+func (s *Service) domain() string {
+	if s.Domain == "" {
+		return "local."
+	}
+	return s.Domain
+}
+
+// serviceName is the "<Service>.<Domain>" name used for PTR lookups,
+// e.g. "_ipp._tcp.local.".
+func (s *Service) serviceName() string {
+	return s.Service + "." + s.domain()
+}
+
+// instanceName is the full "<Instance>.<Service>.<Domain>" name.
+func (s *Service) instanceName() string {
+	return s.Instance + "." + s.serviceName()
+}
+
+// Zone supplies the set of services a Server advertises. Implementations
+// may return a static list or compute it dynamically (e.g. backed by a
+// registry that services can join and leave at runtime).
+type Zone interface {
+	// Services returns every service instance currently advertised in
+	// this zone.
+	Services() []*Service
+}
+
+// ServiceList is a Zone backed by a fixed, in-memory list of services.
+type ServiceList []*Service
+
+// Services implements Zone.
+func (l ServiceList) Services() []*Service { return l }